@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatTTL is how long a worker can go without a heartbeat before the
+// coordinator considers it dead and stops dispatching to it.
+const heartbeatTTL = 30 * time.Second
+
+// taskTimeout bounds how long the coordinator waits for a single worker HTTP
+// call before giving up and retrying the task on another worker.
+const taskTimeout = 60 * time.Second
+
+// maxAttempts is how many different workers the coordinator will try for a
+// single task before giving up and failing the whole job.
+const maxAttempts = 3
+
+// TaskStatus is where a task sits in its lifecycle.
+type TaskStatus string
+
+const (
+	TaskPending TaskStatus = "pending"
+	TaskRunning TaskStatus = "running"
+	TaskDone    TaskStatus = "done"
+	TaskFailed  TaskStatus = "failed"
+)
+
+// Task is one node in a job's split -> map -> reduce DAG.
+type Task struct {
+	ID      string     `json:"id"`
+	Kind    string     `json:"kind"` // split | map | reduce
+	Status  TaskStatus `json:"status"`
+	Attempt int        `json:"attempt"`
+	Worker  string     `json:"worker,omitempty"`
+	Outputs []string   `json:"outputs,omitempty"`
+	Err     string     `json:"error,omitempty"`
+}
+
+// JobSpec is the body of POST /jobs.
+type JobSpec struct {
+	InputS3   string `json:"input_s3"`
+	OutPrefix string `json:"out_prefix"`
+	Parts     int    `json:"parts"`
+	Reducers  int    `json:"reducers"`
+	Job       string `json:"job"`
+}
+
+// Job tracks one job's spec, its task DAG, and overall status. Map and reduce
+// tasks are appended to Tasks once the split they depend on has completed, so
+// the DAG always reflects reality (e.g. fewer chunks than Parts requested if
+// the input is small).
+type Job struct {
+	ID     string     `json:"id"`
+	Spec   JobSpec    `json:"spec"`
+	Status TaskStatus `json:"status"`
+	Tasks  []*Task    `json:"tasks"`
+
+	mu sync.Mutex
+}
+
+// coordinator is the in-memory master: it tracks the live worker pool and
+// every job it has been asked to drive to completion.
+type coordinator struct {
+	mu      sync.Mutex
+	workers map[string]time.Time // worker URL -> last heartbeat
+	jobs    map[string]*Job
+	nextJob int
+
+	client *http.Client
+}
+
+func newCoordinator() *coordinator {
+	return &coordinator{
+		workers: map[string]time.Time{},
+		jobs:    map[string]*Job{},
+		client:  &http.Client{Timeout: taskTimeout},
+	}
+}
+
+// registerWorker adds url to the pool, or refreshes its heartbeat if already present.
+func (co *coordinator) registerWorker(url string) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.workers[url] = time.Now()
+}
+
+// pickWorker returns a live worker (one that has heartbeated within
+// heartbeatTTL) not in exclude, or "" if none is available.
+func (co *coordinator) pickWorker(exclude map[string]bool) string {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	for url, seen := range co.workers {
+		if exclude[url] || time.Since(seen) > heartbeatTTL {
+			continue
+		}
+		return url
+	}
+	return ""
+}
+
+func (co *coordinator) newJob(spec JobSpec) *Job {
+	co.mu.Lock()
+	co.nextJob++
+	id := fmt.Sprintf("job-%d", co.nextJob)
+	job := &Job{
+		ID:     id,
+		Spec:   spec,
+		Status: TaskRunning,
+		Tasks:  []*Task{{ID: "split", Kind: "split", Status: TaskPending}},
+	}
+	co.jobs[id] = job
+	co.mu.Unlock()
+	return job
+}
+
+func (co *coordinator) getJob(id string) (*Job, bool) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	j, ok := co.jobs[id]
+	return j, ok
+}
+
+// dispatch runs a single task through to completion against the worker pool:
+// it tries up to maxAttempts different workers, giving each taskTimeout to
+// respond. A network error, timeout, or 5xx moves on to the next worker so one
+// flaky/crashed worker doesn't sink the task; a 4xx is treated as a bad
+// request and fails the task immediately since retrying would just repeat it.
+// pathFor is called with the chosen worker's URL and the 1-based attempt
+// number so every attempt can write to its own unique S3 output and never
+// collide with a retry of the same task.
+func (co *coordinator) dispatch(ctx context.Context, job *Job, t *Task, pathFor func(worker string, attempt int) string) (json.RawMessage, error) {
+	tried := map[string]bool{}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		worker := co.pickWorker(tried)
+		if worker == "" {
+			return nil, fmt.Errorf("no available worker (last error: %v)", lastErr)
+		}
+		tried[worker] = true
+
+		job.mu.Lock()
+		t.Attempt = attempt
+		t.Worker = worker
+		t.Status = TaskRunning
+		job.mu.Unlock()
+
+		reqCtx, cancel := context.WithTimeout(ctx, taskTimeout)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, pathFor(worker, attempt), nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp, err := co.client.Do(req)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("worker %s: %s", worker, string(body))
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("worker %s: %s", worker, string(body))
+		}
+		return json.RawMessage(body), nil
+	}
+	return nil, fmt.Errorf("exhausted %d attempts: %v", maxAttempts, lastErr)
+}
+
+// runJob drives a job's whole split -> map -> reduce DAG to completion,
+// dispatching each task to the worker pool. It mutates job.Status and each
+// task's Status/Outputs as it goes, so GET /jobs/:id reflects live progress.
+func (co *coordinator) runJob(job *Job) {
+	ctx := context.Background()
+	fail := func(t *Task, err error) {
+		job.mu.Lock()
+		t.Status = TaskFailed
+		t.Err = err.Error()
+		job.Status = TaskFailed
+		job.mu.Unlock()
+	}
+
+	// -------- split --------
+	splitTask := job.Tasks[0]
+	body, err := co.dispatch(ctx, job, splitTask, func(w string, attempt int) string {
+		q := url.Values{}
+		q.Set("input_s3", job.Spec.InputS3)
+		q.Set("out_prefix", fmt.Sprintf("%ssplit-attempt-%d/", job.Spec.OutPrefix, attempt))
+		q.Set("parts", strconv.Itoa(job.Spec.Parts))
+		return fmt.Sprintf("%s/split?%s", w, q.Encode())
+	})
+	if err != nil {
+		fail(splitTask, err)
+		return
+	}
+	var chunks []string
+	if err := json.Unmarshal(body, &chunks); err != nil {
+		fail(splitTask, err)
+		return
+	}
+
+	job.mu.Lock()
+	splitTask.Status = TaskDone
+	splitTask.Outputs = chunks
+	mapTasks := make([]*Task, len(chunks))
+	for i := range chunks {
+		mapTasks[i] = &Task{ID: fmt.Sprintf("map-%d", i), Kind: "map", Status: TaskPending}
+		job.Tasks = append(job.Tasks, mapTasks[i])
+	}
+	job.mu.Unlock()
+
+	// -------- map --------
+	var wg sync.WaitGroup
+	var failMu sync.Mutex
+	mapOK := true
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			t := mapTasks[i]
+			body, err := co.dispatch(ctx, job, t, func(w string, attempt int) string {
+				q := url.Values{}
+				q.Set("chunk_s3", chunk)
+				q.Set("out_prefix", fmt.Sprintf("%smap-%d-attempt-%d/", job.Spec.OutPrefix, i, attempt))
+				q.Set("map_id", strconv.Itoa(i))
+				q.Set("partitions", strconv.Itoa(job.Spec.Reducers))
+				q.Set("job", job.Spec.Job)
+				return fmt.Sprintf("%s/map?%s", w, q.Encode())
+			})
+			var outputs []string
+			if err == nil {
+				err = json.Unmarshal(body, &outputs)
+			}
+			if err != nil {
+				fail(t, err)
+				failMu.Lock()
+				mapOK = false
+				failMu.Unlock()
+				return
+			}
+			job.mu.Lock()
+			t.Status = TaskDone
+			t.Outputs = outputs
+			job.mu.Unlock()
+		}(i, chunk)
+	}
+	wg.Wait()
+	if !mapOK {
+		return
+	}
+
+	job.mu.Lock()
+	reduceTasks := make([]*Task, job.Spec.Reducers)
+	for r := range reduceTasks {
+		reduceTasks[r] = &Task{ID: fmt.Sprintf("reduce-%d", r), Kind: "reduce", Status: TaskPending}
+		job.Tasks = append(job.Tasks, reduceTasks[r])
+	}
+	job.mu.Unlock()
+
+	// -------- reduce --------
+	reduceOK := true
+	for r := range reduceTasks {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			t := reduceTasks[r]
+			ins := make([]string, len(mapTasks))
+			for i, mt := range mapTasks {
+				ins[i] = mt.Outputs[r]
+			}
+			body, err := co.dispatch(ctx, job, t, func(w string, attempt int) string {
+				q := url.Values{}
+				q.Set("r", strconv.Itoa(r))
+				q.Set("job", job.Spec.Job)
+				q.Set("out_prefix", fmt.Sprintf("%sreduce-%d-attempt-%d/", job.Spec.OutPrefix, r, attempt))
+				for _, in := range ins {
+					q.Add("in", in)
+				}
+				return fmt.Sprintf("%s/reduce?%s", w, q.Encode())
+			})
+			var res struct {
+				Output string `json:"output"`
+			}
+			if err == nil {
+				err = json.Unmarshal(body, &res)
+			}
+			if err != nil {
+				fail(t, err)
+				failMu.Lock()
+				reduceOK = false
+				failMu.Unlock()
+				return
+			}
+			job.mu.Lock()
+			t.Status = TaskDone
+			t.Outputs = []string{res.Output}
+			job.mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+
+	if reduceOK {
+		job.mu.Lock()
+		job.Status = TaskDone
+		job.mu.Unlock()
+	}
+}
+
+// setupCoordinator wires up the master's HTTP API: POST /workers to join the
+// pool (also doubling as the heartbeat - re-POSTing refreshes it), POST /jobs
+// to submit a job spec and kick off its DAG, and GET /jobs/:id to watch it run.
+func setupCoordinator(r *gin.Engine) {
+	co := newCoordinator()
+
+	r.POST("/workers", func(c *gin.Context) {
+		var body struct {
+			URL string `json:"url"`
+		}
+		if err := c.BindJSON(&body); err != nil || body.URL == "" {
+			c.String(http.StatusBadRequest, "url is required")
+			return
+		}
+		co.registerWorker(body.URL)
+		c.JSON(200, gin.H{"registered": body.URL})
+	})
+
+	r.POST("/jobs", func(c *gin.Context) {
+		var spec JobSpec
+		if err := c.BindJSON(&spec); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if spec.Parts < 1 {
+			spec.Parts = 1
+		}
+		if spec.Reducers < 1 {
+			spec.Reducers = 1
+		}
+		if spec.Job == "" {
+			c.String(http.StatusBadRequest, "job is required")
+			return
+		}
+
+		job := co.newJob(spec)
+		go co.runJob(job)
+		c.JSON(200, gin.H{"id": job.ID})
+	})
+
+	r.GET("/jobs/:id", func(c *gin.Context) {
+		job, ok := co.getJob(c.Param("id"))
+		if !ok {
+			c.String(http.StatusNotFound, "job not found")
+			return
+		}
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		c.JSON(200, job)
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPickWorkerSkipsExpiredHeartbeats(t *testing.T) {
+	co := newCoordinator()
+	co.workers["http://stale"] = time.Now().Add(-heartbeatTTL - time.Second)
+	co.workers["http://fresh"] = time.Now()
+
+	if got := co.pickWorker(nil); got != "http://fresh" {
+		t.Fatalf("pickWorker = %q, want http://fresh", got)
+	}
+}
+
+func TestPickWorkerHonorsExclude(t *testing.T) {
+	co := newCoordinator()
+	co.registerWorker("http://a")
+	co.registerWorker("http://b")
+
+	if got := co.pickWorker(map[string]bool{"http://a": true}); got != "http://b" {
+		t.Fatalf("pickWorker = %q, want http://b", got)
+	}
+	if got := co.pickWorker(map[string]bool{"http://a": true, "http://b": true}); got != "" {
+		t.Fatalf("pickWorker with every worker excluded = %q, want \"\"", got)
+	}
+}
+
+// TestDispatchMovesOnAfterServerErrorOrNetworkFailure registers one worker
+// that always 500s, one that's unreachable, and one that succeeds, then
+// checks dispatch retries past the first two and returns the good worker's
+// body - regardless of which order pickWorker happens to try them in.
+func TestDispatchMovesOnAfterServerErrorOrNetworkFailure(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer bad.Close()
+
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"ok"`))
+	}))
+	defer good.Close()
+
+	co := newCoordinator()
+	co.registerWorker(bad.URL)
+	co.registerWorker("http://127.0.0.1:1") // nothing listens here: connection refused
+	co.registerWorker(good.URL)
+
+	job := &Job{}
+	task := &Task{}
+	body, err := co.dispatch(context.Background(), job, task, func(w string, attempt int) string {
+		return w + "/work"
+	})
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	if string(body) != `"ok"` {
+		t.Fatalf("body = %q, want \"ok\"", body)
+	}
+}
+
+// TestDispatchFailsImmediatelyOn4xx checks a 4xx response is treated as a bad
+// request and does not get retried against another worker.
+func TestDispatchFailsImmediatelyOn4xx(t *testing.T) {
+	calls := 0
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("nope"))
+	}))
+	defer bad.Close()
+
+	co := newCoordinator()
+	co.registerWorker(bad.URL)
+
+	job := &Job{}
+	task := &Task{}
+	_, err := co.dispatch(context.Background(), job, task, func(w string, attempt int) string {
+		return w + "/work"
+	})
+	if err == nil {
+		t.Fatal("dispatch: expected an error from a 4xx response")
+	}
+	if calls != 1 {
+		t.Fatalf("worker was called %d times, want exactly 1 (no retry on 4xx)", calls)
+	}
+}
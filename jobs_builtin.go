@@ -0,0 +1,99 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var wordRE = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+func init() {
+	Register("wordcount", wordCountMap, sumReduce)
+	Register("invertedindex", postingsMap, invertedIndexReduce)
+	Register("docfreq", postingsMap, docFreqReduce)
+	Register("sum", sumJobMap, sumReduce)
+}
+
+// wordCountMap tokenizes line into lowercase [A-Za-z0-9]+ words and emits one
+// (word, 1) per occurrence - the job=wordcount built-in, and the tokenization
+// the original hardcoded /map handler used.
+func wordCountMap(line string, emit func(key string, value int)) {
+	for _, w := range wordRE.FindAllString(strings.ToLower(line), -1) {
+		emit(w, 1)
+	}
+}
+
+// sumReduce adds up every value seen for key. Shared by the wordcount and sum
+// jobs, both of which just want a running total.
+func sumReduce(key string, values []int) []int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return []int{total}
+}
+
+// postingsMap expects each line in the form "<docID>\t<text>" and emits
+// (word, docID) for every word in text. Shared by the job=invertedindex and
+// job=docfreq built-ins, which differ only in how they reduce the resulting
+// docID list. A docID can recur per word (the same word more than once in a
+// document), so both reducers have to fold repeats out.
+func postingsMap(line string, emit func(key string, value int)) {
+	docID, text, ok := strings.Cut(line, "\t")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(docID)
+	if err != nil {
+		return
+	}
+	for _, w := range wordRE.FindAllString(strings.ToLower(text), -1) {
+		emit(w, id)
+	}
+}
+
+// invertedIndexReduce folds a word's observed docIDs down to its sorted,
+// deduplicated postings list - the job=invertedindex built-in. Reducer
+// returning []int rather than a single int is what makes delivering the full
+// list of documents (not just how many) possible.
+func invertedIndexReduce(key string, values []int) []int {
+	seen := map[int]struct{}{}
+	for _, v := range values {
+		seen[v] = struct{}{}
+	}
+	postings := make([]int, 0, len(seen))
+	for id := range seen {
+		postings = append(postings, id)
+	}
+	sort.Ints(postings)
+	return postings
+}
+
+// docFreqReduce folds a word's observed docIDs down to its document frequency
+// - the count of distinct documents it appears in - the job=docfreq built-in,
+// for callers who only want df and would rather not carry the full postings
+// list through every partition file.
+func docFreqReduce(key string, values []int) []int {
+	seen := map[int]struct{}{}
+	for _, v := range values {
+		seen[v] = struct{}{}
+	}
+	return []int{len(seen)}
+}
+
+// sumJobMap expects each line in the form "<key>\t<value>" and emits (key,
+// value) unchanged - the job=sum built-in for aggregating arbitrary numeric
+// columns.
+func sumJobMap(line string, emit func(key string, value int)) {
+	k, v, ok := strings.Cut(line, "\t")
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return
+	}
+	emit(k, n)
+}
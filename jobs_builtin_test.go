@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestInvertedIndexReduceReturnsSortedDistinctPostings(t *testing.T) {
+	got := invertedIndexReduce("word", []int{3, 1, 3, 2, 1})
+	want := []int{1, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDocFreqReduceCountsDistinctDocs(t *testing.T) {
+	got := docFreqReduce("word", []int{3, 1, 3, 2, 1})
+	if len(got) != 1 || got[0] != 3 {
+		t.Fatalf("got %v, want [3]", got)
+	}
+}
+
+func TestPostingsMapEmitsWordDocIDPairs(t *testing.T) {
+	var got []struct {
+		key string
+		val int
+	}
+	postingsMap("7\tthe Cat sat", func(key string, value int) {
+		got = append(got, struct {
+			key string
+			val int
+		}{key, value})
+	})
+
+	want := []string{"the", "cat", "sat"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d pairs, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].key != w || got[i].val != 7 {
+			t.Fatalf("pair %d = (%q, %d), want (%q, 7)", i, got[i].key, got[i].val, w)
+		}
+	}
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
@@ -24,17 +25,90 @@ func parseS3(url string) (string, string) {
 	return trim[:i], trim[i+1:]
 }
 
-// use AWS SDK to create S3 client, uploader, and downloader
+// getEnv returns the value of the given environment variable, or fallback if it is unset or empty.
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getEnvInt returns the integer value of the given environment variable, or
+// fallback if it is unset, empty, or not a valid integer.
+func getEnvInt(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// use AWS SDK to create S3 client, uploader, and downloader.
+// Region, endpoint and credentials are all configurable via env vars so this
+// service can be pointed at an S3-compatible store (MinIO, Ceph RadosGW, ...)
+// instead of real AWS, e.g. for local integration testing.
+//
+//	S3_ENDPOINT              - custom endpoint URL (leave unset to use real AWS)
+//	S3_REGION                - region, defaults to "us-east-1"
+//	S3_ACCESS_KEY            - static access key (requires S3_SECRET_KEY)
+//	S3_SECRET_KEY            - static secret key (requires S3_ACCESS_KEY)
+//	S3_FORCE_PATH_STYLE      - "true" to use path-style addressing (required by most non-AWS stores)
+//	S3_MAX_RETRIES           - max attempts per S3 request, defaults to 5; the SDK's
+//	                           standard retryer already backs off exponentially between them
+//	S3_DOWNLOAD_PART_SIZE    - downloader part size in bytes, defaults to the SDK's 5 MiB
+//	S3_DOWNLOAD_CONCURRENCY  - number of parts the downloader fetches in parallel, defaults to the SDK's 5
 func mustAws() (*s3.Client, *manager.Uploader, *manager.Downloader) {
-	// config.LoadDefaultConfig: loads the default AWS configuration, specifying the region as "us-east-1".
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion("us-east-1"))
+	region := getEnv("S3_REGION", "us-east-1")
+	endpoint := os.Getenv("S3_ENDPOINT")
+	accessKey := os.Getenv("S3_ACCESS_KEY")
+	secretKey := os.Getenv("S3_SECRET_KEY")
+	maxRetries := int(getEnvInt("S3_MAX_RETRIES", 5))
+
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithRetryMaxAttempts(maxRetries),
+	}
+
+	// static credentials override the default chain (env/shared config/IMDS)
+	// so a MinIO/RadosGW deployment doesn't need real AWS credentials lying around.
+	if accessKey != "" && secretKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")))
+	}
+
+	// a custom endpoint resolver redirects every S3 call at a non-AWS host.
+	if endpoint != "" {
+		resolver := aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: endpoint, SigningRegion: region, HostnameImmutable: true}, nil
+			})
+		opts = append(opts, config.WithEndpointResolverWithOptions(resolver))
+	}
+
+	// config.LoadDefaultConfig: loads the AWS configuration with the region/credentials/endpoint overrides above applied.
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		panic(err)
 	}
-	// s3.NewFromConfig: creates a new S3 client using the loaded configuration.
-	client := s3.NewFromConfig(cfg)
-	// manager.NewUploader and manager.NewDownloader: create an uploader and downloader for S3 operations.
-	return client, manager.NewUploader(client), manager.NewDownloader(client)
+	// s3.NewFromConfig: creates a new S3 client, forcing path-style addressing if requested.
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if getEnv("S3_FORCE_PATH_STYLE", "false") == "true" {
+			o.UsePathStyle = true
+		}
+	})
+	// manager.NewUploader and manager.NewDownloader: create an uploader with the SDK's defaults and
+	// a downloader whose part size/concurrency are tuned via env vars for throughput on large chunks. Each ranged
+	// GET a part requires still goes through the client's retryer above, so a single flaky part
+	// doesn't fail the whole download.
+	downloader := manager.NewDownloader(client, func(d *manager.Downloader) {
+		d.PartSize = getEnvInt("S3_DOWNLOAD_PART_SIZE", manager.DefaultDownloadPartSize)
+		d.Concurrency = int(getEnvInt("S3_DOWNLOAD_CONCURRENCY", int64(manager.DefaultDownloadConcurrency)))
+	})
+	return client, manager.NewUploader(client), downloader
 }
 
 func main() {
@@ -43,10 +117,19 @@ func main() {
 	// initialize a gin router
 	r := gin.Default()
 
-	_, uploader, downloader := mustAws()
+	client, uploader, downloader := mustAws()
+
+	// -------- COORDINATOR --------
+	// drives the whole split -> map -> reduce pipeline across a pool of workers
+	// registered via POST /workers, instead of a client having to call
+	// /split, /map and /reduce by hand.
+	if mode == "coordinator" {
+		setupCoordinator(r)
+	}
 
 	// -------- SPLITTER --------
-	//split input file into smaller chunks and upload to S3.
+	// split input file into smaller chunks and upload to S3, streaming throughout
+	// so memory use stays bounded regardless of input size.
 	r.GET("/split", func(c *gin.Context) {
 
 		// if ip is splitter task but mode is not "splitter", return an error
@@ -58,164 +141,138 @@ func main() {
 		// get input parameters from query string
 		// input_s3: S3 URL of the input file to be split
 		// out_prefix: S3 prefix for the output chunks
-		// parts: number of parts to split into 3
+		// parts: number of parts to split into, defaults to 3
 		input := c.Query("input_s3")
-		parts := 3
 		prefix := c.Query("out_prefix")
+		parts := 3
+		if p := c.Query("parts"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				c.String(http.StatusBadRequest, "parts must be a positive integer")
+				return
+			}
+			parts = n
+		}
 
 		// parse the S3 URL to get the bucket and key
 		// parseS3: function to parse S3 URL and return bucket and key
 		bucket, key := parseS3(input)
 
-		// Creates a memory buffer to hold the downloaded file content.
-		buf := manager.NewWriteAtBuffer([]byte{})
-		// Downloads the file from S3 into the buffer.
-		// downloader.Download: downloads the file from S3 into the buffer.
-		//context.Background() is an empty context. and means no timeout or cancellation.
-		_, err := downloader.Download(context.Background(), buf,
-			&s3.GetObjectInput{Bucket: &bucket, Key: &key})
+		// streamSplit balances the input by byte offset (rebalanced onto line
+		// boundaries) and streams each chunk to S3 via a multipart upload, so the
+		// whole file is never held in memory at once.
+		urls, err := streamSplit(context.Background(), client, uploader, bucket, key, prefix, parts)
 		if err != nil {
 			c.String(500, err.Error())
 			return
 		}
-		// Converts the buffer content to a string and splits it into lines.
-		content := string(buf.Bytes())
-		lines := strings.Split(content, "\n")
-
-		// we use ceiling division to calculate the size of each chunk and split the lines into chunks.
-		chunkSize := (len(lines) + parts - 1) / parts
-		urls := []string{}
-		for i := 0; i < parts; i++ {
-			start := i * chunkSize
-			end := (i + 1) * chunkSize
-			if end > len(lines) {
-				end = len(lines)
-			}
-			chunk := strings.Join(lines[start:end], "\n")
-
-			// Creates the output filename. like prefix/chunk-0.txt.
-			outKey := fmt.Sprintf("%schunk-%d.txt", strings.TrimSuffix(prefix, "/")+"/", i)
-
-			// Uploads the chunk to S3 using the uploader.
-			_, err := uploader.Upload(context.Background(),
-				&s3.PutObjectInput{
-					Bucket: &bucket,
-					Key:    &outKey,
-					Body:   strings.NewReader(chunk),
-				})
-			if err != nil {
-				c.String(500, err.Error())
-				return
-			}
-			// Appends the S3 URL of the uploaded chunk to the urls slice. like s3://mybucket/results/chunk-0.txt.
-			urls = append(urls, fmt.Sprintf("s3://%s/%s", bucket, outKey))
-		}
 		c.JSON(200, urls)
 	})
 
 	// -------- MAPPER --------
-	// read a chunk from S3, count word occurrences, and write the result back to S3.
+	// read a chunk from S3, run it through the selected job's Mapper/Reducer
+	// (the reducer doubling as the in-chunk combiner), then partition the
+	// combined values into `partitions` sorted TSV files so the reduce side can
+	// run a bounded-memory streaming merge instead of loading every mapper's
+	// whole output into one process.
 	r.GET("/map", func(c *gin.Context) {
 		if mode != "mapper" {
 			c.String(http.StatusBadRequest, "This task is not a mapper")
 			return
 		}
 
+		// job selects the registered Mapper/Reducer pair to run; see Register.
+		j, ok := lookupJob(c.Query("job"))
+		if !ok {
+			c.String(http.StatusBadRequest, fmt.Sprintf("unknown job %q", c.Query("job")))
+			return
+		}
+
 		// Reads query parameter chunk_s3, which is the S3 URL of one chunk file. like s3://mybucket/results/chunk-0.txt
 		chunkS3 := c.Query("chunk_s3")
-		// Reads query parameter out_s3, which is the S3 URL where the mapper should write its output. like s3://mybucket/results/map-output-0.json
-		outS3 := c.Query("out_s3")
+		// Reads query parameter out_prefix, the key prefix (in the chunk's own bucket) to write partition files under.
+		outPrefix := c.Query("out_prefix")
+		// map_id identifies this mapper task; it becomes the <m> in part-<m>-<r>.tsv.
+		mapID, err := strconv.Atoi(c.Query("map_id"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "map_id must be an integer")
+			return
+		}
+		// partitions is R, the number of reduce partitions to hash keys into.
+		partitions, err := strconv.Atoi(c.Query("partitions"))
+		if err != nil || partitions < 1 {
+			c.String(http.StatusBadRequest, "partitions must be a positive integer")
+			return
+		}
 
 		// parse the S3 URL to get the bucket and key
 		bucket, key := parseS3(chunkS3)
-		// Creates a memory buffer to hold the downloaded chunk file.
-		buf := manager.NewWriteAtBuffer([]byte{})
 
-		// Downloads the chunk file from S3 into a buffer.
-		_, err := downloader.Download(context.Background(), buf,
-			&s3.GetObjectInput{Bucket: &bucket, Key: &key})
+		// mapChunk streams the chunk through a concurrent ranged download (tuned
+		// via S3_DOWNLOAD_PART_SIZE/S3_DOWNLOAD_CONCURRENCY) so the tokenizer runs
+		// alongside the download instead of waiting for it to finish, then folds
+		// every key's emitted values through the job's Reducer.
+		results, err := mapChunk(context.Background(), downloader, j, bucket, key)
 		if err != nil {
 			c.String(500, err.Error())
 			return
 		}
 
-		// Converts the buffer content to a string.
-		text := string(buf.Bytes())
-		// Uses a regular expression to find all words in the text and counts their occurrences.
-		re := regexp.MustCompile(`[A-Za-z0-9]+`)
-		counts := map[string]int{}
-		// Convert everything to lowercase and count occurrences
-		for _, w := range re.FindAllString(strings.ToLower(text), -1) {
-			counts[w]++
-		}
-
-		// Marshals the word count map to JSON.
-		js, _ := json.Marshal(counts)
-
-		// Uploads the JSON result to the specified S3 output location.
-		outBucket, outKey := parseS3(outS3)
-		_, err = uploader.Upload(context.Background(),
-			&s3.PutObjectInput{
-				Bucket: &outBucket,
-				Key:    &outKey,
-				Body:   strings.NewReader(string(js)),
-			})
+		// writePartitions hashes each key into one of `partitions` buckets, sorts
+		// each bucket, and uploads it as part-<map_id>-<r>.tsv.
+		urls, err := writePartitions(context.Background(), uploader, results, bucket, outPrefix, mapID, partitions)
 		if err != nil {
 			c.String(500, err.Error())
 			return
 		}
-		c.JSON(200, gin.H{"output": outS3})
+		c.JSON(200, urls)
 	})
 
 	// -------- REDUCER --------
-	// read multiple mapper outputs from S3, aggregate the word counts, and write the final result back to S3.
+	// read every mapper's sorted TSV output for one partition, k-way merge them
+	// through the selected job's Reducer in bounded memory, and write the
+	// partition's final sorted "part-<r>.tsv".
 	r.GET("/reduce", func(c *gin.Context) {
 		if mode != "reducer" {
 			c.String(http.StatusBadRequest, "This task is not a reducer")
 			return
 		}
 
-		// Reads query parameter in, which can appear multiple times to specify multiple S3 URLs of mapper output files. /reduce?in=s3://bucket/map-0.json&in=s3://bucket/map-1.json&out_s3=s3://bucket/final.json
-		inputs := c.QueryArray("in")
-		// Reads query parameter out_s3, which is the S3 URL where the reducer should write its final output.
-		outS3 := c.Query("out_s3")
-		// Initializes a map to hold the aggregated word counts.
-		total := map[string]int{}
-
-		for _, in := range inputs {
-			// get each mapper bucket and key from S3
-			bucket, key := parseS3(in)
-			// download each mapper output into a buffer
-			buf := manager.NewWriteAtBuffer([]byte{})
-			_, err := downloader.Download(context.Background(), buf,
-				&s3.GetObjectInput{Bucket: &bucket, Key: &key})
-			if err != nil {
-				c.String(500, err.Error())
-				return
-			}
-			// unmarshal the JSON content of each mapper output into a map and aggregate the counts into the total map.
-			m := map[string]int{}
-			json.Unmarshal(buf.Bytes(), &m)
-			// aggregate the counts into the total map.
-			for k, v := range m {
-				total[k] += v
-			}
+		// job selects the registered Mapper/Reducer pair to run; see Register.
+		j, ok := lookupJob(c.Query("job"))
+		if !ok {
+			c.String(http.StatusBadRequest, fmt.Sprintf("unknown job %q", c.Query("job")))
+			return
 		}
 
-		// convert the total map to JSON and upload it to the specified S3 output location.
-		js, _ := json.Marshal(total)
-		outBucket, outKey := parseS3(outS3)
-		_, err := uploader.Upload(context.Background(),
-			&s3.PutObjectInput{
-				Bucket: &outBucket,
-				Key:    &outKey,
-				Body:   strings.NewReader(string(js)),
-			})
+		// r is the partition index this reducer owns; it names the output
+		// part-<r>.tsv. The in files are read as given: nothing checks that they
+		// are the partition-r files the mappers wrote.
+		r, err := strconv.Atoi(c.Query("r"))
 		if err != nil {
+			c.String(http.StatusBadRequest, "r must be an integer")
+			return
+		}
+		// Reads query parameter in, which can appear multiple times to specify each mapper's
+		// sorted partition file for this r. /reduce?r=0&job=wordcount&in=s3://bucket/part-0-0.tsv&in=s3://bucket/part-1-0.tsv&out_prefix=results/
+		inputs := c.QueryArray("in")
+		if len(inputs) == 0 {
+			c.String(http.StatusBadRequest, "at least one in is required")
+			return
+		}
+		// out_prefix is the key prefix (in the inputs' own bucket) to write the
+		// partition's final part-<r>.tsv under.
+		outPrefix := c.Query("out_prefix")
+
+		outBucket, _ := parseS3(inputs[0])
+		outKey := fmt.Sprintf("%spart-%d.tsv", strings.TrimSuffix(outPrefix, "/")+"/", r)
+		// mergePartitions streams a k-way merge of the already-sorted inputs,
+		// folding each key's values through the job's Reducer, in bounded memory.
+		if err := mergePartitions(context.Background(), client, uploader, inputs, outBucket, outKey, j.Reduce); err != nil {
 			c.String(500, err.Error())
 			return
 		}
-		c.JSON(200, gin.H{"output": outS3})
+		c.JSON(200, gin.H{"output": fmt.Sprintf("s3://%s/%s", outBucket, outKey)})
 	})
 
 	r.Run(":8080")
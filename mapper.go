@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// orderedWriterAt implements s3manager's io.WriterAt by buffering the
+// concurrent, out-of-order part writes a Downloader performs and flushing them
+// to an underlying io.Writer strictly in offset order. Wrapping an io.Pipe in
+// this lets the mapper consume the download as a single ordered stream - so
+// the tokenizer runs concurrently with the download - while the downloader
+// still gets the throughput win of fetching Concurrency parts in parallel.
+type orderedWriterAt struct {
+	mu      sync.Mutex
+	w       io.Writer
+	next    int64
+	pending map[int64][]byte
+}
+
+func newOrderedWriterAt(w io.Writer) *orderedWriterAt {
+	return &orderedWriterAt{w: w, pending: map[int64][]byte{}}
+}
+
+func (o *orderedWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[off] = append([]byte(nil), b...)
+	for {
+		chunk, ok := o.pending[o.next]
+		if !ok {
+			break
+		}
+		if _, err := o.w.Write(chunk); err != nil {
+			return 0, err
+		}
+		delete(o.pending, o.next)
+		o.next += int64(len(chunk))
+	}
+	return len(b), nil
+}
+
+// mapChunk streams bucket/key through the job's Mapper line by line - reading
+// through the Downloader's concurrent ranged GETs into orderedWriterAt and an
+// io.Pipe instead of an in-memory buffer, so the tokenizer can start working on
+// the first lines while later parts are still in flight - and folds every key's emitted values through the job's Reducer,
+// the combine-is-the-reducer pattern real MapReduce uses.
+func mapChunk(ctx context.Context, downloader *manager.Downloader, j job, bucket, key string) (map[string][]int, error) {
+	pr, pw := io.Pipe()
+	wa := newOrderedWriterAt(pw)
+
+	go func() {
+		_, err := downloader.Download(ctx, wa, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+		pw.CloseWithError(err)
+	}()
+
+	values := map[string][]int{}
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		j.Map(scanner.Text(), func(k string, v int) {
+			values[k] = append(values[k], v)
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]int, len(values))
+	for k, vs := range values {
+		results[k] = j.Reduce(k, vs)
+	}
+	return results, nil
+}
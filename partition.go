@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// partitionFor hashes key into one of r partitions, deciding which reducer owns it.
+func partitionFor(key string, r int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(r))
+}
+
+// writePartitions buckets results into r partitions by key hash, sorts each
+// partition's keys, and writes it as a "key\tv1,v2,...\n" TSV to
+// bucket/outPrefix/part-<mapID>-<r>.tsv. It returns the S3 URL of every
+// partition file written, in partition order, so the reducer for partition r
+// only ever has to read the URL at index r from every mapper.
+func writePartitions(ctx context.Context, uploader *manager.Uploader, results map[string][]int, bucket, outPrefix string, mapID, r int) ([]string, error) {
+	buckets := make([][]string, r)
+	for k := range results {
+		p := partitionFor(k, r)
+		buckets[p] = append(buckets[p], k)
+	}
+
+	urls := make([]string, r)
+	for p, keys := range buckets {
+		sort.Strings(keys)
+		var sb strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "%s\t%s\n", k, joinInts(results[k]))
+		}
+
+		outKey := fmt.Sprintf("%spart-%d-%d.tsv", strings.TrimSuffix(outPrefix, "/")+"/", mapID, p)
+		_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &outKey,
+			Body:   strings.NewReader(sb.String()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		urls[p] = fmt.Sprintf("s3://%s/%s", bucket, outKey)
+	}
+	return urls, nil
+}
+
+// joinInts renders values as a comma-separated TSV field.
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseInts parses a joinInts-formatted comma-separated field back into ints,
+// skipping any field that fails to parse.
+func parseInts(s string) []int {
+	fields := strings.Split(s, ",")
+	values := make([]int, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// tsvEntry is one "key\tv1,v2,..." row read from a partition file, tagged
+// with the index of the file it came from so the heap knows which scanner to
+// advance.
+type tsvEntry struct {
+	key    string
+	values []int
+	src    int
+}
+
+// entryHeap is a min-heap of tsvEntry ordered by key, used to k-way merge
+// already-sorted partition files without loading any of them fully into memory.
+type entryHeap []tsvEntry
+
+func (h entryHeap) Len() int            { return len(h) }
+func (h entryHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h entryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x interface{}) { *h = append(*h, x.(tsvEntry)) }
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
+
+// nextEntry reads and parses the next "key\tv1,v2,..." line from sc, tagging
+// the result with src so the caller knows which scanner produced it.
+func nextEntry(sc *bufio.Scanner, src int) (tsvEntry, bool) {
+	if !sc.Scan() {
+		return tsvEntry{}, false
+	}
+	fields := strings.SplitN(sc.Text(), "\t", 2)
+	if len(fields) != 2 {
+		return tsvEntry{}, false
+	}
+	return tsvEntry{key: fields[0], values: parseInts(fields[1]), src: src}, true
+}
+
+// mergePartitions performs a streaming k-way merge (container/heap) of the
+// already-sorted TSV partition files at inputs, all belonging to the same
+// reduce partition, folding every value seen for a key through reduce, and
+// writes the fully sorted result to outBucket/outKey. Memory use is bounded by
+// the number of input files, not their total size, so the reducer scales to an
+// arbitrarily large vocabulary.
+func mergePartitions(ctx context.Context, client s3Getter, uploader *manager.Uploader, inputs []string, outBucket, outKey string, reduce Reducer) error {
+	scanners := make([]*bufio.Scanner, len(inputs))
+	bodies := make([]io.ReadCloser, len(inputs))
+	defer func() {
+		for _, b := range bodies {
+			if b != nil {
+				b.Close()
+			}
+		}
+	}()
+
+	for i, in := range inputs {
+		bucket, key := parseS3(in)
+		out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+		if err != nil {
+			return err
+		}
+		bodies[i] = out.Body
+		scanners[i] = bufio.NewScanner(out.Body)
+	}
+
+	h := &entryHeap{}
+	heap.Init(h)
+	for i, sc := range scanners {
+		if e, ok := nextEntry(sc, i); ok {
+			heap.Push(h, e)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for h.Len() > 0 {
+			top := heap.Pop(h).(tsvEntry)
+			key := top.key
+			values := append([]int(nil), top.values...)
+			if e, ok := nextEntry(scanners[top.src], top.src); ok {
+				heap.Push(h, e)
+			}
+			// the merge is globally sorted, so any other file sharing this key
+			// sits right at the top of the heap now - fold it in before moving on.
+			for h.Len() > 0 && (*h)[0].key == key {
+				dup := heap.Pop(h).(tsvEntry)
+				values = append(values, dup.values...)
+				if e, ok := nextEntry(scanners[dup.src], dup.src); ok {
+					heap.Push(h, e)
+				}
+			}
+			result := reduce(key, values)
+			if _, err := fmt.Fprintf(pw, "%s\t%s\n", key, joinInts(result)); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
+
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: &outBucket,
+		Key:    &outKey,
+		Body:   pr,
+	})
+	return err
+}
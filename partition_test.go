@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestMergePartitionsFoldsDuplicateKeysAcrossFiles(t *testing.T) {
+	fake := newFakeS3()
+	uploader := manager.NewUploader(fake)
+	const bucket = "bkt"
+
+	// apple appears in both mapper's partition files, so the merge has to land
+	// on the heap's duplicate-key branch and fold both counts together.
+	fake.put(bucket, "part-0-0.tsv", []byte("apple\t2\nbanana\t1\n"))
+	fake.put(bucket, "part-1-0.tsv", []byte("apple\t3\ncherry\t5\n"))
+
+	inputs := []string{"s3://bkt/part-0-0.tsv", "s3://bkt/part-1-0.tsv"}
+	if err := mergePartitions(context.Background(), fake, uploader, inputs, bucket, "part-0.tsv", sumReduce); err != nil {
+		t.Fatalf("mergePartitions: %v", err)
+	}
+
+	got := string(fake.objects[fakeKey(bucket, "part-0.tsv")])
+	want := "apple\t5\nbanana\t1\ncherry\t5\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMergePartitionsSingleInputPassesThrough(t *testing.T) {
+	fake := newFakeS3()
+	uploader := manager.NewUploader(fake)
+	const bucket = "bkt"
+
+	fake.put(bucket, "part-0-0.tsv", []byte("alpha\t2\nzeta\t1\n"))
+
+	inputs := []string{"s3://bkt/part-0-0.tsv"}
+	if err := mergePartitions(context.Background(), fake, uploader, inputs, bucket, "part-0.tsv", sumReduce); err != nil {
+		t.Fatalf("mergePartitions: %v", err)
+	}
+
+	got := string(fake.objects[fakeKey(bucket, "part-0.tsv")])
+	want := "alpha\t2\nzeta\t1\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+// Mapper processes one line of an input chunk, emitting zero or more (key,
+// value) pairs via emit. A job's Mapper describes what a single /map line
+// contributes to the overall computation.
+type Mapper func(line string, emit func(key string, value int))
+
+// Reducer folds every value observed for a key into that key's final result,
+// itself a list of ints so a job can report something richer than a single
+// count - e.g. invertedindex's full sorted, deduplicated docID postings list.
+// A job that only wants a total (wordcount, sum) just returns a one-element
+// slice. It is used both as the combiner inside /map (to fold a chunk's own
+// repeated keys down before partitioning) and as the reducer inside /reduce
+// (to fold the same key's values back together across every mapper's
+// partition file).
+type Reducer func(key string, values []int) []int
+
+// job bundles the Mapper/Reducer pair registered under a single job name.
+type job struct {
+	Map    Mapper
+	Reduce Reducer
+}
+
+var registry = map[string]job{}
+
+// Register makes a Mapper/Reducer pair available as job=<name> on /map and
+// /reduce. Everything here lives in package main, so there's no package to
+// import from the outside - adding a job means adding a Go file to this
+// binary (see jobs_builtin.go) that calls Register from its own init() and
+// rebuilding, e.g.:
+//
+//	func init() {
+//		Register("my-job", myMapper, myReducer)
+//	}
+func Register(name string, m Mapper, r Reducer) {
+	registry[name] = job{Map: m, Reduce: r}
+}
+
+// lookupJob returns the job registered under name, or false if none is.
+func lookupJob(name string) (job, bool) {
+	j, ok := registry[name]
+	return j, ok
+}
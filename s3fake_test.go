@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 is a minimal in-memory stand-in for *s3.Client: just enough of the
+// API (HeadObject, ranged GetObject, PutObject and the multipart upload trio)
+// for streamSplit/mergePartitions/mapChunk and the manager.Uploader/Downloader
+// they use to run against it in tests, without touching real AWS.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	parts   map[string]map[int32][]byte // uploadID -> partNumber -> data
+	keys    map[string]string           // uploadID -> "bucket/key"
+	nextID  int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: map[string][]byte{},
+		parts:   map[string]map[int32][]byte{},
+		keys:    map[string]string{},
+	}
+}
+
+func fakeKey(bucket, key string) string { return bucket + "/" + key }
+
+// put seeds an object directly, as if it had already been uploaded.
+func (f *fakeS3) put(bucket, key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[fakeKey(bucket, key)] = data
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	data, ok := f.objects[fakeKey(*in.Bucket, *in.Key)]
+	f.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakeS3: no such object %s", fakeKey(*in.Bucket, *in.Key))
+	}
+	size := int64(len(data))
+	return &s3.HeadObjectOutput{ContentLength: &size}, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	data, ok := f.objects[fakeKey(*in.Bucket, *in.Key)]
+	f.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakeS3: no such object %s", fakeKey(*in.Bucket, *in.Key))
+	}
+
+	size := int64(len(data))
+	start, end := int64(0), size
+	if in.Range != nil {
+		var err error
+		start, end, err = parseFakeRange(*in.Range, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// real S3 answers a range starting at or past EOF with 416 Requested Range
+	// Not Satisfiable rather than an empty/negative slice, so the downloader
+	// sees an error instead of panicking on an out-of-bounds slice.
+	if start < 0 || start >= size {
+		return nil, fmt.Errorf("fakeS3: range start %d out of bounds for %d-byte object", start, size)
+	}
+
+	contentLength := end - start
+	out := &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(data[start:end])),
+		ContentLength: &contentLength,
+	}
+	// like real S3, a ranged GET reports the object's total size in
+	// Content-Range, which the downloader uses to plan the remaining parts.
+	if in.Range != nil {
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", start, end-1, size)
+		out.ContentRange = &contentRange
+	}
+	return out, nil
+}
+
+// parseFakeRange parses a "bytes=start-end" Range header into a half-open
+// [start,end) interval, clamped to size.
+func parseFakeRange(r string, size int64) (int64, int64, error) {
+	r = strings.TrimPrefix(r, "bytes=")
+	fields := strings.SplitN(r, "-", 2)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("fakeS3: bad range %q", r)
+	}
+	start, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	end++ // the header is inclusive; we want exclusive
+	if end > size {
+		end = size
+	}
+	return start, end, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.put(*in.Bucket, *in.Key, data)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	id := fmt.Sprintf("upload-%d", f.nextID)
+	f.parts[id] = map[int32][]byte{}
+	f.keys[id] = fakeKey(*in.Bucket, *in.Key)
+	return &s3.CreateMultipartUploadOutput{UploadId: &id}, nil
+}
+
+func (f *fakeS3) UploadPart(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	etag := fmt.Sprintf("etag-%d", in.PartNumber)
+	f.mu.Lock()
+	f.parts[*in.UploadId][*in.PartNumber] = data
+	f.mu.Unlock()
+	return &s3.UploadPartOutput{ETag: &etag}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	parts := f.parts[*in.UploadId]
+	key := f.keys[*in.UploadId]
+	delete(f.parts, *in.UploadId)
+	delete(f.keys, *in.UploadId)
+	f.mu.Unlock()
+
+	nums := make([]int32, 0, len(parts))
+	for n := range parts {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	var buf bytes.Buffer
+	for _, n := range nums {
+		buf.Write(parts[n])
+	}
+
+	f.mu.Lock()
+	f.objects[key] = buf.Bytes()
+	f.mu.Unlock()
+
+	loc := "https://fake/" + key
+	return &s3.CompleteMultipartUploadOutput{Location: &loc}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(_ context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	delete(f.parts, *in.UploadId)
+	delete(f.keys, *in.UploadId)
+	f.mu.Unlock()
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
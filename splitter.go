@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxLineSize bounds how long a single line may be before the scanner gives up;
+// this keeps a corrupt/binary input from growing the line buffer without limit.
+const maxLineSize = 1 << 20 // 1 MiB
+
+// seekWindow bounds how far past a naive split point we scan for the next newline
+// when aligning a chunk boundary onto a line.
+const seekWindow = 64 * 1024
+
+// s3Getter is the subset of *s3.Client the streaming helpers below need.
+// Taking this narrow interface instead of *s3.Client directly lets tests run
+// streamSplit/mergePartitions against an in-memory fake instead of real AWS.
+type s3Getter interface {
+	GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, in *s3.HeadObjectInput, opts ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// streamSplit divides bucket/key into `parts` roughly-equal byte ranges (rebalanced
+// onto line boundaries via HeadObject + ranged GetObject) and streams each range's
+// lines straight into its own chunk via a multipart upload, writing all parts
+// concurrently. It never buffers the whole input in memory, so it scales to inputs
+// far larger than the pod's RAM.
+func streamSplit(ctx context.Context, client s3Getter, uploader *manager.Uploader, bucket, key, outPrefix string, parts int) ([]string, error) {
+	size, err := objectSize(ctx, client, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := lineBoundaries(ctx, client, bucket, key, size, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(offsets) - 1
+	urls := make([]string, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		start, end := offsets[i], offsets[i+1]
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			outKey := fmt.Sprintf("%schunk-%d.txt", strings.TrimSuffix(outPrefix, "/")+"/", i)
+			if err := streamRangeToS3(ctx, client, uploader, bucket, key, start, end, bucket, outKey); err != nil {
+				errs[i] = err
+				return
+			}
+			urls[i] = fmt.Sprintf("s3://%s/%s", bucket, outKey)
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// ranges shorter than one line collapse to empty and are dropped, so callers
+	// only ever see chunks that were actually written.
+	out := urls[:0]
+	for _, u := range urls {
+		if u != "" {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// objectSize returns the size in bytes of bucket/key via HeadObject.
+func objectSize(ctx context.Context, client s3Getter, bucket, key string) (int64, error) {
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return 0, err
+	}
+	if head.ContentLength == nil {
+		return 0, fmt.Errorf("head object s3://%s/%s: missing content length", bucket, key)
+	}
+	return *head.ContentLength, nil
+}
+
+// lineBoundaries picks parts+1 byte offsets that evenly divide [0,size] and nudges
+// every interior offset forward to the start of the next line, so every resulting
+// range starts and ends on a line boundary.
+func lineBoundaries(ctx context.Context, client s3Getter, bucket, key string, size int64, parts int) ([]int64, error) {
+	if parts < 1 {
+		parts = 1
+	}
+	offsets := make([]int64, parts+1)
+	offsets[0] = 0
+	offsets[parts] = size
+
+	chunk := size / int64(parts)
+	for i := 1; i < parts; i++ {
+		naive := int64(i) * chunk
+		aligned, err := nextLineStart(ctx, client, bucket, key, naive, size)
+		if err != nil {
+			return nil, err
+		}
+		offsets[i] = aligned
+	}
+	return offsets, nil
+}
+
+// nextLineStart returns the offset of the first byte after the next '\n' at or
+// after pos, scanning forward in bounded windows so a missing newline doesn't
+// force reading the rest of the object into memory.
+func nextLineStart(ctx context.Context, client s3Getter, bucket, key string, pos, size int64) (int64, error) {
+	for pos < size {
+		end := pos + seekWindow
+		if end > size {
+			end = size
+		}
+		body, err := rangeReader(ctx, client, bucket, key, pos, end)
+		if err != nil {
+			return 0, err
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return 0, err
+		}
+		if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+			return pos + int64(idx) + 1, nil
+		}
+		pos = end
+	}
+	return size, nil
+}
+
+// rangeReader opens a ranged GetObject read over the half-open interval [start, end)
+// of bucket/key.
+func rangeReader(ctx context.Context, client s3Getter, bucket, key string, start, end int64) (io.ReadCloser, error) {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", start, end-1)
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Range:  &rangeHeader,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// streamRangeToS3 copies the line-bounded byte range [start,end) of srcBucket/srcKey
+// straight into a multipart upload at dstBucket/dstKey via io.Pipe, so the chunk is
+// never held in memory in full. It re-splits on '\n' with bufio.Scanner rather than
+// copying raw bytes so a future caller can hook per-line processing in here.
+// Lines are rejoined with '\n' and no newline follows the last one, so the
+// chunk lacks the range's final '\n'; a '\r' before a '\n' is dropped as well.
+func streamRangeToS3(ctx context.Context, client s3Getter, uploader *manager.Uploader, srcBucket, srcKey string, start, end int64, dstBucket, dstKey string) error {
+	body, err := rangeReader(ctx, client, srcBucket, srcKey, start, end)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		first := true
+		for scanner.Scan() {
+			if !first {
+				if _, err := pw.Write([]byte("\n")); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			}
+			first = false
+			if _, err := pw.Write(scanner.Bytes()); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(scanner.Err())
+	}()
+
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket: &dstBucket,
+		Key:    &dstKey,
+		Body:   pr,
+	})
+	return err
+}
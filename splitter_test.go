@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestLineBoundariesAlignOnNewlines(t *testing.T) {
+	fake := newFakeS3()
+	const bucket, key = "bkt", "in.txt"
+	content := "alpha\nbeta\ngamma\ndelta\nepsilon\n"
+	fake.put(bucket, key, []byte(content))
+
+	offsets, err := lineBoundaries(context.Background(), fake, bucket, key, int64(len(content)), 3)
+	if err != nil {
+		t.Fatalf("lineBoundaries: %v", err)
+	}
+	if offsets[0] != 0 || offsets[len(offsets)-1] != int64(len(content)) {
+		t.Fatalf("offsets should span the whole object, got %v", offsets)
+	}
+	for _, off := range offsets[1 : len(offsets)-1] {
+		if off > 0 && content[off-1] != '\n' {
+			t.Errorf("offset %d does not follow a newline in %q", off, content)
+		}
+	}
+}
+
+func TestStreamRangeToS3CopiesExactRange(t *testing.T) {
+	fake := newFakeS3()
+	uploader := manager.NewUploader(fake)
+	const bucket, key = "bkt", "in.txt"
+	content := "one\ntwo\nthree\n"
+	fake.put(bucket, key, []byte(content))
+
+	if err := streamRangeToS3(context.Background(), fake, uploader, bucket, key, 4, 13, bucket, "out.txt"); err != nil {
+		t.Fatalf("streamRangeToS3: %v", err)
+	}
+
+	got := string(fake.objects[fakeKey(bucket, "out.txt")])
+	if want := "two\nthree"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestStreamSplitPreservesEveryLine(t *testing.T) {
+	fake := newFakeS3()
+	uploader := manager.NewUploader(fake)
+	const bucket, key = "bkt", "in.txt"
+	lines := []string{"alpha", "beta", "gamma", "delta", "epsilon", "zeta", "eta"}
+	content := strings.Join(lines, "\n")
+	fake.put(bucket, key, []byte(content))
+
+	urls, err := streamSplit(context.Background(), fake, uploader, bucket, key, "out/", 4)
+	if err != nil {
+		t.Fatalf("streamSplit: %v", err)
+	}
+	if len(urls) == 0 {
+		t.Fatal("streamSplit returned no chunks")
+	}
+
+	var reassembled []string
+	for _, u := range urls {
+		_, k := parseS3(u)
+		data, ok := fake.objects[fakeKey(bucket, k)]
+		if !ok {
+			t.Fatalf("chunk %s was never written", u)
+		}
+		reassembled = append(reassembled, strings.Split(string(data), "\n")...)
+	}
+
+	if got := strings.Join(reassembled, "\n"); got != content {
+		t.Fatalf("reassembled chunks = %q, want %q", got, content)
+	}
+}
+
+func TestStreamSplitNoInputDoesNotPanic(t *testing.T) {
+	fake := newFakeS3()
+	uploader := manager.NewUploader(fake)
+	const bucket, key = "bkt", "empty.txt"
+	fake.put(bucket, key, []byte(""))
+
+	urls, err := streamSplit(context.Background(), fake, uploader, bucket, key, "out/", 3)
+	if err != nil {
+		t.Fatalf("streamSplit: %v", err)
+	}
+	if len(urls) != 0 {
+		t.Fatalf("expected no chunks for an empty input, got %v", urls)
+	}
+}